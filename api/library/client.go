@@ -0,0 +1,271 @@
+// Package library implements the subset of the Google Photos Library API that
+// gphotosuploader needs: resumable media uploads and turning an upload token into
+// a media item, replacing the cookie-scraping flow in package api for users who
+// opt into --backend=oauth.
+package library
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"gphotosuploader/orm/models"
+)
+
+const uploadsEndpoint = "https://photoslibrary.googleapis.com/v1/uploads"
+const mediaItemsEndpoint = "https://photoslibrary.googleapis.com/v1/mediaItems:batchCreate"
+const albumsEndpoint = "https://photoslibrary.googleapis.com/v1/albums"
+const shareAlbumEndpointTemplate = "https://photoslibrary.googleapis.com/v1/albums/%s:share"
+
+// HTTPDoer is satisfied by *http.Client (including the one returned from
+// auth/oauth.Credentials.HTTPClient), kept narrow so this package doesn't need to
+// import the oauth package directly.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client talks to the Google Photos Library API on behalf of an authenticated user.
+type Client struct {
+	http HTTPDoer
+}
+
+// NewClient wraps an already-authorized HTTP client so it can be used for library
+// API calls.
+func NewClient(httpClient HTTPDoer) *Client {
+	return &Client{http: httpClient}
+}
+
+// ResumableUpload uploads path to the Library API's uploads endpoint, resuming
+// the session in file.UploadURL when it's already set on the record (from the
+// offset Google reports having received, not the stored file.ResumeOffset),
+// and returns the upload token CreateMediaItem needs to finish the job. file is
+// updated in place so the caller can persist the new offset/URL if interrupted.
+// onProgress, if non-nil, is called with the number of bytes written as the
+// upload streams.
+func (c *Client) ResumableUpload(path string, file *models.File, onProgress func(bytesWritten int64)) (uploadToken string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("can't open %q for upload: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("can't stat %q: %w", path, err)
+	}
+
+	if file.UploadURL == "" {
+		uploadURL, err := c.startUpload(info.Name())
+		if err != nil {
+			return "", err
+		}
+		file.UploadURL = uploadURL
+		file.ResumeOffset = 0
+	} else {
+		// An upload session already exists for this file (left over from an
+		// interrupted run); file.ResumeOffset is never persisted mid-transfer, so
+		// the only way to know how many bytes Google actually received is to ask.
+		offset, err := c.queryOffset(file.UploadURL)
+		if err != nil {
+			return "", err
+		}
+		file.ResumeOffset = offset
+	}
+
+	if _, err := f.Seek(file.ResumeOffset, io.SeekStart); err != nil {
+		return "", fmt.Errorf("can't seek %q to offset %d: %w", path, file.ResumeOffset, err)
+	}
+
+	var body io.Reader = f
+	if onProgress != nil {
+		body = &progressReader{r: f, onProgress: onProgress}
+	}
+	return c.uploadAndFinalize(file.UploadURL, body, file.ResumeOffset, info.Size())
+}
+
+// progressReader reports each chunk it reads from r via onProgress, so
+// ResumableUpload's caller can track live throughput without this package
+// depending on package storage.
+type progressReader struct {
+	r          io.Reader
+	onProgress func(bytesWritten int64)
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	if n > 0 {
+		p.onProgress(int64(n))
+	}
+	return n, err
+}
+
+func (c *Client) startUpload(fileName string) (uploadURL string, err error) {
+	req, err := http.NewRequest("POST", uploadsEndpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("can't build start-upload request: %w", err)
+	}
+	req.Header.Set("X-Goog-Upload-Protocol", "resumable")
+	req.Header.Set("X-Goog-Upload-Command", "start")
+	req.Header.Set("X-Goog-Upload-Content-Type", "application/octet-stream")
+	req.Header.Set("X-Goog-Upload-File-Name", fileName)
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("can't start resumable upload: %w", err)
+	}
+	defer res.Body.Close()
+
+	uploadURL = res.Header.Get("X-Goog-Upload-URL")
+	if uploadURL == "" {
+		return "", fmt.Errorf("Google Photos didn't return an upload URL")
+	}
+	return uploadURL, nil
+}
+
+func (c *Client) queryOffset(uploadURL string) (int64, error) {
+	req, err := http.NewRequest("POST", uploadURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("can't build query-offset request: %w", err)
+	}
+	req.Header.Set("X-Goog-Upload-Command", "query")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("can't query upload offset: %w", err)
+	}
+	defer res.Body.Close()
+
+	sizeReceived := res.Header.Get("X-Goog-Upload-Size-Received")
+	if sizeReceived == "" {
+		return 0, nil
+	}
+
+	var offset int64
+	if _, err := fmt.Sscanf(sizeReceived, "%d", &offset); err != nil {
+		return 0, fmt.Errorf("can't parse X-Goog-Upload-Size-Received %q: %w", sizeReceived, err)
+	}
+	return offset, nil
+}
+
+func (c *Client) uploadAndFinalize(uploadURL string, body io.Reader, offset, totalSize int64) (string, error) {
+	req, err := http.NewRequest("POST", uploadURL, body)
+	if err != nil {
+		return "", fmt.Errorf("can't build upload request: %w", err)
+	}
+	req.Header.Set("X-Goog-Upload-Command", "upload, finalize")
+	req.Header.Set("X-Goog-Upload-Offset", fmt.Sprintf("%d", offset))
+	req.ContentLength = totalSize - offset
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("can't upload file contents: %w", err)
+	}
+	defer res.Body.Close()
+
+	token, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("can't read upload token: %w", err)
+	}
+	return string(token), nil
+}
+
+// CreateMediaItem turns an upload token into a media item, adding it to albumID
+// (pass "" to add it to the library without placing it in a specific album).
+func (c *Client) CreateMediaItem(uploadToken, albumID string) error {
+	payload := map[string]interface{}{
+		"newMediaItems": []map[string]interface{}{{
+			"simpleMediaItem": map[string]string{"uploadToken": uploadToken},
+		}},
+	}
+	if albumID != "" {
+		payload["albumId"] = albumID
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("can't encode batchCreate request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", mediaItemsEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("can't build batchCreate request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't create media item: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("batchCreate returned status %s", res.Status)
+	}
+	return nil
+}
+
+// CreateAlbum creates a new album titled name and returns its album ID.
+func (c *Client) CreateAlbum(name string) (albumID string, err error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"album": map[string]string{"title": name},
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't encode create-album request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", albumsEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("can't build create-album request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("can't create album %q: %w", name, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("create-album for %q returned status %s", name, res.Status)
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("can't parse create-album response: %w", err)
+	}
+	return created.ID, nil
+}
+
+// ShareAlbum makes albumID accessible to anyone with the returned URL.
+func (c *Client) ShareAlbum(albumID string) (shareURL string, err error) {
+	req, err := http.NewRequest("POST", fmt.Sprintf(shareAlbumEndpointTemplate, albumID), bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return "", fmt.Errorf("can't build share-album request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("can't share album %q: %w", albumID, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("share-album for %q returned status %s", albumID, res.Status)
+	}
+
+	var shared struct {
+		ShareInfo struct {
+			ShareableURL string `json:"shareableUrl"`
+		} `json:"shareInfo"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&shared); err != nil {
+		return "", fmt.Errorf("can't parse share-album response: %w", err)
+	}
+	return shared.ShareInfo.ShareableURL, nil
+}
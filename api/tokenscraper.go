@@ -9,9 +9,8 @@ import (
 	"net/http"
 	"strings"
 
-	"log"
+	"gphotosuploader/auth"
 
-	"github.com/garfieldkwong/gphotosuploader/auth"
 	"golang.org/x/net/html"
 )
 
@@ -89,6 +88,12 @@ func findScript(page *http.Response) (string, error) {
 	}
 }
 
+// ApiTokenContainer models the JSON object Google Photos assigns to a global
+// variable in the bootstrap script; SNlM0e is the field holding the at token.
+type ApiTokenContainer struct {
+	Token string `json:"SNlM0e"`
+}
+
 func findTokenInScript(script string) (string, error) {
 	// The script assigns an object to the global window object. We are going to parse the script as a JSON
 	// so we need to get rid of the assignment code
@@ -97,7 +102,7 @@ func findTokenInScript(script string) (string, error) {
 	start := equalsIndex + 1
 	end := len(script) - 1
 	script = script[start:end]
-        log.Println("xxxx", script);
+
 	object := ApiTokenContainer{}
 	if err := json.NewDecoder(strings.NewReader(script)).Decode(&object); err != nil {
 		return "", fmt.Errorf("can't parse the JSON object that contains the at token (%v)", err)
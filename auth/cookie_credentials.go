@@ -0,0 +1,99 @@
+// Package auth holds the credential types used to authenticate against Google
+// Photos. CookieCredentials implements the original cookie + at-token scraping
+// flow; it's kept as the "legacy" backend so existing users aren't forced onto
+// OAuth2 immediately. See package auth/oauth for the supported replacement.
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+)
+
+const googlePhotosURL = "https://photos.google.com/"
+
+// RuntimeParameters holds values derived at runtime rather than loaded from the
+// auth file, such as the short-lived at token used to authorize upload requests.
+type RuntimeParameters struct {
+	AtToken string
+}
+
+// CookieCredentials bundles the cookies scraped from a logged-in browser session
+// with the http.Client built from them and the runtime parameters derived later.
+type CookieCredentials struct {
+	Client            *http.Client      `json:"-"`
+	Cookies           []*http.Cookie    `json:"cookies"`
+	RuntimeParameters RuntimeParameters `json:"-"`
+}
+
+// CredentialsValidity reports whether a set of credentials is still usable.
+type CredentialsValidity struct {
+	Valid  bool
+	Reason string
+}
+
+// NewCookieCredentials builds CookieCredentials from cookies already scraped from
+// a browser session, installing them into a fresh http.Client's cookie jar.
+func NewCookieCredentials(cookies []*http.Cookie) (*CookieCredentials, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't create cookie jar: %w", err)
+	}
+
+	target, _ := url.Parse(googlePhotosURL)
+	jar.SetCookies(target, cookies)
+
+	return &CookieCredentials{
+		Client:  &http.Client{Jar: jar},
+		Cookies: cookies,
+	}, nil
+}
+
+// NewCookieCredentialsFromFile loads previously serialized cookies from disk and
+// rebuilds an http.Client with them installed in its cookie jar.
+func NewCookieCredentialsFromFile(path string) (*CookieCredentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read auth file %q: %w", path, err)
+	}
+
+	var raw CookieCredentials
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("can't parse auth file %q: %w", path, err)
+	}
+
+	return NewCookieCredentials(raw.Cookies)
+}
+
+// CheckCredentials makes a lightweight request to Google Photos to confirm the
+// cookies are still accepted before the caller relies on them for a full run.
+func (c *CookieCredentials) CheckCredentials() (CredentialsValidity, error) {
+	req, err := http.NewRequest("GET", googlePhotosURL, nil)
+	if err != nil {
+		return CredentialsValidity{}, fmt.Errorf("can't build validity check request: %w", err)
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return CredentialsValidity{}, fmt.Errorf("can't reach Google Photos: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized || res.Request.URL.Path == "/signin" {
+		return CredentialsValidity{Valid: false, Reason: "session cookies have expired, please sign in again"}, nil
+	}
+
+	return CredentialsValidity{Valid: true}, nil
+}
+
+// SerializeToFile writes the cookies to path so a later run can skip the sign-in.
+func (c *CookieCredentials) SerializeToFile(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't serialize credentials: %w", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,155 @@
+// Package oauth implements the OAuth2 authorization-code-with-PKCE flow used to
+// authenticate against the Google Photos Library API. It replaces the cookie
+// scraping in package auth for users who opt into the "oauth" backend.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// scope is the narrowest Google Photos Library API scope that still allows
+// creating and uploading media items.
+const scope = "https://www.googleapis.com/auth/photoslibrary.appendonly"
+
+// redirectURI is the loopback address the local callback server listens on while
+// waiting for the authorization code redirect.
+const redirectURI = "http://127.0.0.1:8732/oauth/callback"
+
+// Credentials holds the OAuth2 config and token so callers can mint authorized HTTP
+// clients without worrying about refreshing expired access tokens themselves.
+type Credentials struct {
+	config *oauth2.Config
+	token  *oauth2.Token
+}
+
+// NewCredentialsFromFile loads a previously persisted token (including its refresh
+// token) from authFile and wraps it in a Credentials ready to authorize requests.
+func NewCredentialsFromFile(clientID, clientSecret, authFile string) (*Credentials, error) {
+	data, err := os.ReadFile(authFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read oauth auth file %q: %w", authFile, err)
+	}
+
+	var token oauth2.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("can't parse oauth auth file %q: %w", authFile, err)
+	}
+
+	return &Credentials{config: newConfig(clientID, clientSecret), token: &token}, nil
+}
+
+// Authenticate runs the authorization-code-with-PKCE flow: it prints the consent
+// URL for the user to open, waits for the redirect on a local loopback server,
+// exchanges the code (with the PKCE verifier) for a token, and persists the result
+// to authFile so future runs can call NewCredentialsFromFile instead.
+func Authenticate(clientID, clientSecret, authFile string) (*Credentials, error) {
+	config := newConfig(clientID, clientSecret)
+
+	verifier, challenge, err := newPKCEPair()
+	if err != nil {
+		return nil, fmt.Errorf("can't generate PKCE verifier: %w", err)
+	}
+
+	authURL := config.AuthCodeURL("state",
+		oauth2.SetAuthURLParam("code_challenge", challenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	fmt.Printf("Open this URL in your browser to authorize gphotosuploader:\n%s\n", authURL)
+
+	code, err := waitForAuthorizationCode()
+	if err != nil {
+		return nil, fmt.Errorf("can't complete the browser authorization: %w", err)
+	}
+
+	token, err := config.Exchange(context.Background(), code,
+		oauth2.SetAuthURLParam("code_verifier", verifier),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't exchange authorization code for a token: %w", err)
+	}
+
+	creds := &Credentials{config: config, token: token}
+	if err := creds.SerializeToFile(authFile); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// SerializeToFile persists the current token, including its refresh token, to
+// authFile.
+func (c *Credentials) SerializeToFile(authFile string) error {
+	data, err := json.MarshalIndent(c.token, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't serialize oauth token: %w", err)
+	}
+	return os.WriteFile(authFile, data, 0600)
+}
+
+// HTTPClient returns an *http.Client that transparently refreshes the access token
+// from the stored refresh token, suitable for use by api/library.Client.
+func (c *Credentials) HTTPClient() *http.Client {
+	return c.config.Client(context.Background(), c.token)
+}
+
+func newConfig(clientID, clientSecret string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     google.Endpoint,
+		RedirectURL:  redirectURI,
+		Scopes:       []string{scope},
+	}
+}
+
+func newPKCEPair() (verifier, challenge string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(raw)
+
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier, challenge, nil
+}
+
+// waitForAuthorizationCode starts a short-lived HTTP server on the loopback
+// redirect URI and blocks until Google redirects the browser back with a code.
+func waitForAuthorizationCode() (string, error) {
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+
+	server := &http.Server{Addr: "127.0.0.1:8732"}
+	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if errMsg := r.URL.Query().Get("error"); errMsg != "" {
+			errCh <- fmt.Errorf("authorization was denied: %s", errMsg)
+			fmt.Fprintln(w, "Authorization denied, you can close this tab.")
+			return
+		}
+		codeCh <- r.URL.Query().Get("code")
+		fmt.Fprintln(w, "Authorization complete, you can close this tab.")
+	})
+
+	go server.ListenAndServe()
+	defer server.Close()
+
+	select {
+	case code := <-codeCh:
+		return code, nil
+	case err := <-errCh:
+		return "", err
+	case <-time.After(5 * time.Minute):
+		return "", fmt.Errorf("timed out waiting for the browser redirect")
+	}
+}
@@ -0,0 +1,74 @@
+// Package exif reads the metadata gphotosuploader uses to route files into
+// albums: capture date, GPS coordinates and camera model.
+package exif
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	goexif "github.com/rwcarlsen/goexif/exif"
+)
+
+// Metadata is the subset of a file's EXIF tags used for album routing.
+type Metadata struct {
+	DateTimeOriginal time.Time
+	Latitude         float64
+	Longitude        float64
+	Camera           string
+}
+
+// TemplateData is what --albumTemplate is evaluated against. Year/Month/Day come
+// from DateTimeOriginal when present, otherwise from the file's mtime.
+type TemplateData struct {
+	Year   int
+	Month  int
+	Day    int
+	Camera string
+}
+
+// Read extracts Metadata from path's EXIF tags, returning an error if the file
+// has none; callers should fall back to the file's mtime and a default album.
+func Read(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("can't open %q: %w", path, err)
+	}
+	defer f.Close()
+
+	x, err := goexif.Decode(f)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("can't decode EXIF from %q: %w", path, err)
+	}
+
+	var meta Metadata
+	if dt, err := x.DateTime(); err == nil {
+		meta.DateTimeOriginal = dt
+	}
+	if lat, long, err := x.LatLong(); err == nil {
+		meta.Latitude, meta.Longitude = lat, long
+	}
+	if tag, err := x.Get(goexif.Model); err == nil {
+		if camera, err := tag.StringVal(); err == nil {
+			meta.Camera = camera
+		}
+	}
+
+	return meta, nil
+}
+
+// Template builds the values --albumTemplate is evaluated against, using mtime
+// when m has no DateTimeOriginal (EXIF decoding failed, or the tag was absent).
+func (m Metadata) Template(mtime time.Time) TemplateData {
+	date := m.DateTimeOriginal
+	if date.IsZero() {
+		date = mtime
+	}
+
+	return TemplateData{
+		Year:   date.Year(),
+		Month:  int(date.Month()),
+		Day:    date.Day(),
+		Camera: m.Camera,
+	}
+}
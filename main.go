@@ -7,17 +7,20 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
-	"strings"
 	"syscall"
 	"time"
 
-	"gphotosuploader/api"
-	"gphotosuploader/auth"
 	"gphotosuploader/orm"
 	"gphotosuploader/orm/models"
+	"gphotosuploader/progress"
+	"gphotosuploader/server"
+	"gphotosuploader/storage"
 	"gphotosuploader/utils"
 	"gphotosuploader/version"
 
+	_ "gphotosuploader/storage/googlephotos"
+	_ "gphotosuploader/storage/localfs"
+
 	"github.com/fsnotify/fsnotify"
 )
 
@@ -28,21 +31,27 @@ var (
 	directoriesToWatch   utils.DirectoriesToWatch
 	albumId              string
 	albumName            string
+	albumTemplate        string
+	defaultAlbum         string
 	watchRecursively     bool
 	maxConcurrentUploads int
 	eventDelay           time.Duration
 	printVersion         bool
 	patternsToIgnore     utils.PatternsToIgnore
 	reuploadFailed       bool
+	backendName          string
+	backendConfig        string
+	rehash               bool
+	statsJSONPath        string
+	silent               bool
+	noProgress           bool
+	listenAddr           string
+	listenToken          string
 
 	// Uploader
 	uploader *utils.ConcurrentUploader
 	timers   = make(map[string]*time.Timer)
-
-	// Statistics
-	uploadedFilesCount = 0
-	ignoredCount       = 0
-	errorsCount        = 0
+	reporter *progress.Reporter
 )
 
 func main() {
@@ -52,14 +61,41 @@ func main() {
 		os.Exit(0)
 	}
 
-	credentials := initAuthentication()
+	if rehash {
+		rehashExistingFiles()
+		os.Exit(0)
+	}
+
+	config := backendConfig
+	if config == "" && backendName == "legacy" {
+		config = authFile
+	}
 
-	var err error
-	uploader, err = utils.NewUploader(credentials, albumId, albumName, maxConcurrentUploads)
+	backend, err := storage.New(backendName, config)
+	if err != nil {
+		log.Fatalf("Can't create %q backend: %v\n", backendName, err)
+	}
+
+	uploader, err = utils.NewUploader(backend, albumId, albumName, albumTemplate, defaultAlbum, maxConcurrentUploads)
 	if err != nil {
 		log.Fatalf("Can't create uploader: %v\n", err)
 	}
 
+	reporter = progress.NewReporter(maxConcurrentUploads, silent, noProgress)
+
+	if listenAddr != "" {
+		if listenToken == "" {
+			log.Fatalf("--listen requires --listen-token, so the control API's mutating endpoints aren't wide open\n")
+		}
+		srv := server.New(uploader, backend, reporter, listenToken)
+		go func() {
+			if err := srv.ListenAndServe(listenAddr); err != nil {
+				log.Printf("Control API stopped: %v\n", err)
+			}
+		}()
+		log.Printf("Control API listening on %s\n", listenAddr)
+	}
+
 	stopHandler := make(chan bool)
 	go handleUploaderEvents(stopHandler)
 
@@ -71,23 +107,29 @@ func main() {
 	// Wait until all the uploads are completed
 	uploader.WaitUploadsCompleted()
 
-	// Start to watch all the directories if needed
-	if len(directoriesToWatch) > 0 {
-		watcher, err := fsnotify.NewWatcher()
-		if err != nil {
-			panic(err)
-		}
-		defer watcher.Close()
-		go handleFileSystemEvents(watcher, stopHandler)
-
-		// Add all the directories passed as argument to the watcher
-		for _, name := range directoriesToWatch {
-			if err := startToWatch(name, watcher); err != nil {
+	// Start to watch all the directories if needed, and/or stay up to keep
+	// serving the control API; both are long-running uses that only exit on
+	// CTRL+C, unlike a plain one-shot --upload run.
+	if len(directoriesToWatch) > 0 || listenAddr != "" {
+		if len(directoriesToWatch) > 0 {
+			watcher, err := fsnotify.NewWatcher()
+			if err != nil {
 				panic(err)
 			}
-		}
+			defer watcher.Close()
+			go handleFileSystemEvents(watcher, stopHandler)
+
+			// Add all the directories passed as argument to the watcher
+			for _, name := range directoriesToWatch {
+				if err := startToWatch(name, watcher); err != nil {
+					panic(err)
+				}
+			}
 
-		log.Println("Watching 👀\nPress CTRL + C to stop")
+			log.Println("Watching 👀\nPress CTRL + C to stop")
+		} else {
+			log.Println("Control API running\nPress CTRL + C to stop")
+		}
 
 		// Wait for CTRL + C
 		c := make(chan os.Signal, 2)
@@ -100,7 +142,15 @@ func main() {
 	stopHandler <- true
 	<-stopHandler
 
-	log.Printf("Done (%v files uploaded, %v files ignored, %v errors)", uploadedFilesCount, ignoredCount, errorsCount)
+	reporter.Finish()
+	if summary := reporter.Summary(); summary != "" {
+		log.Println(summary)
+	}
+	if statsJSONPath != "" {
+		if err := reporter.WriteStatsJSON(statsJSONPath); err != nil {
+			log.Printf("Can't write --stats-json: %v\n", err)
+		}
+	}
 	os.Exit(0)
 }
 
@@ -110,6 +160,8 @@ func parseCliArguments() {
 	flag.Var(&filesToUpload, "upload", "File or directory to upload")
 	flag.StringVar(&albumId, "album", "", "Use this parameter to move new images to a specific album")
 	flag.StringVar(&albumName, "albumName", "", "Use this parameter to move new images to a new album")
+	flag.StringVar(&albumTemplate, "albumTemplate", "", "Go text/template (e.g. '{{.Year}}-{{.Month}}' or '{{.Camera}}') evaluated against each file's EXIF metadata to pick its album; overrides --album/--albumName")
+	flag.StringVar(&defaultAlbum, "defaultAlbum", "", "Album to use with --albumTemplate when the template evaluates to an empty name for a file (e.g. '{{.Camera}}' on a file without EXIF)")
 	flag.IntVar(&maxConcurrentUploads, "maxConcurrent", 1, "Number of max concurrent uploads")
 	flag.Var(&directoriesToWatch, "watch", "Directory to watch")
 	flag.BoolVar(&watchRecursively, "watchRecursively", true, "Start watching new directories in currently watched directories")
@@ -117,6 +169,14 @@ func parseCliArguments() {
 	flag.BoolVar(&printVersion, "version", false, "Print version and commit date")
 	flag.Var(&patternsToIgnore, "ignore", "Patterns to ignore")
 	flag.BoolVar(&reuploadFailed, "reupload", false, "Re-upload the failed files")
+	flag.StringVar(&backendName, "backend", "legacy", "Storage backend to use: 'legacy', 'oauth' or 'localfs'")
+	flag.StringVar(&backendConfig, "backend-config", "", "Backend-specific configuration string, see the chosen backend's docs")
+	flag.BoolVar(&rehash, "rehash", false, "Backfill the sha256/size/mtime columns for rows created before they existed, then exit")
+	flag.StringVar(&statsJSONPath, "stats-json", "", "Write a machine-readable run summary (counts, bytes, failure classes, upload latency percentiles) to this path at shutdown")
+	flag.BoolVar(&silent, "silent", false, "Suppress progress bars and the final summary line, for cron/CI use")
+	flag.BoolVar(&noProgress, "no-progress", false, "Suppress progress bars but keep the final summary line")
+	flag.StringVar(&listenAddr, "listen", "", "Address (e.g. ':8080') to serve the HTTP control API on; disabled when empty. A bare ':port' binds loopback only")
+	flag.StringVar(&listenToken, "listen-token", "", "Bearer token required on every control API endpoint except /api/share/redeem; required when --listen is set")
 
 	flag.Parse()
 
@@ -124,58 +184,27 @@ func parseCliArguments() {
 	eventDelay = time.Duration(*delay) * time.Second
 }
 
-func initAuthentication() auth.CookieCredentials {
-	// Load authentication parameters
-	credentials, err := auth.NewCookieCredentialsFromFile(authFile)
-	if err != nil {
-		log.Printf("Can't use '%v' as auth file\n", authFile)
-		credentials = nil
-	} else {
-		log.Println("Auth file loaded, checking validity ...")
-		validity, err := credentials.CheckCredentials()
-		if err != nil {
-			log.Fatalf("Can't check validity of credentials (%v)\n", err)
-			credentials = nil
-		} else if !validity.Valid {
-			log.Printf("Credentials are not valid! %v\n", validity.Reason)
-			credentials = nil
-		} else {
-			log.Println("Auth file seems to be valid")
-		}
-	}
-
-	if credentials == nil {
-		fmt.Println("The uploader can't continue without valid authentication tokens ...")
-		fmt.Println("Would you like to run the WebDriver CookieCredentials Wizard ? [Yes/No]")
-		fmt.Println("(If you don't know what it is, refer to the README)")
-
-		var answer string
-		fmt.Scanln(&answer)
-		startWizard := len(answer) > 0 && strings.ToLower(answer)[0] == 'y'
+// rehashExistingFiles backfills SHA256/Size/MTime on rows left over from before
+// those columns existed, so the duplicate-content check in EnqueueUpload can see
+// them too.
+func rehashExistingFiles() {
+	var files []models.File
+	orm.GetInstance().Connection.Where("sha256 = ? OR sha256 IS NULL", "").Find(&files)
 
-		if !startWizard {
-			log.Fatalln("It's not possible to continue, sorry!")
-		} else {
-			credentials, err = utils.StartWebDriverCookieCredentialsWizard()
-			if err != nil {
-				log.Fatalf("Can't complete the login wizard, got: %v\n", err)
-			} else {
-				// TODO: Handle error
-				credentials.SerializeToFile(authFile)
-			}
+	for _, f := range files {
+		sum, size, mtime, err := utils.HashFile(f.Path)
+		if err != nil {
+			log.Printf("Can't hash %q: %v\n", f.Path, err)
+			continue
 		}
-	}
 
-	// Get a new At token
-	log.Println("Getting a new At token ...")
-	token, err := api.NewAtTokenScraper(*credentials).ScrapeNewAtToken()
-	if err != nil {
-		log.Fatalf("Can't scrape a new At token (%v)\n", err)
+		f.SHA256 = sum
+		f.Size = size
+		f.MTime = mtime
+		orm.GetInstance().Connection.Save(&f)
 	}
-	credentials.RuntimeParameters.AtToken = token
-	log.Println("At token taken")
 
-	return *credentials
+	log.Printf("Rehashed %d file(s)\n", len(files))
 }
 
 // Check whether the path need to ignore
@@ -213,19 +242,32 @@ func reuploadFailedFiles() {
 }
 
 func handleUploaderEvents(exiting chan bool) {
+	uploadStarted := make(map[string]time.Time)
+
 	for {
 		select {
-		case info := <-uploader.CompletedUploads:
-			uploadedFilesCount++
-			log.Printf("Upload of '%v' completed\n", info)
+		case ev := <-uploader.Progress:
+			if ev.Started {
+				uploadStarted[ev.Path] = time.Now()
+			}
+			reporter.HandleProgress(ev)
+
+		case result := <-uploader.CompletedUploads:
+			elapsed := time.Since(uploadStarted[result.Path])
+			delete(uploadStarted, result.Path)
+			reporter.RecordUploaded(result, elapsed)
 
 		case info := <-uploader.IgnoredUploads:
-			ignoredCount++
-			log.Printf("Not uploading '%v', it's already been uploaded or it's not a image/video!\n", info)
+			reporter.RecordIgnored()
+			if !silent {
+				log.Printf("Not uploading '%v', it's already been uploaded or it's not a image/video!\n", info)
+			}
 
 		case err := <-uploader.Errors:
-			log.Printf("Upload error: %v\n", err)
-			errorsCount++
+			reporter.RecordFailed(err)
+			if !silent {
+				log.Printf("Upload error: %v\n", err)
+			}
 
 		case <-exiting:
 			exiting <- true
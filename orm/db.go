@@ -29,7 +29,7 @@ func GetInstance() *DB {
 		if err != nil {
 			panic("failed to connect database")
 		}
-		instance.Connection.AutoMigrate(&models.File{})
+		instance.Connection.AutoMigrate(&models.File{}, &models.Album{}, &models.ShareToken{})
 		if err != nil {
 			panic("failed to connect database")
 		}
@@ -0,0 +1,11 @@
+package models
+
+import "gorm.io/gorm"
+
+// Album caches the backend album ID resolved for a given name, so the per-file
+// album resolver only has to call Backend.CreateAlbum once per album name.
+type Album struct {
+	gorm.Model
+	Name     string `gorm:"uniqueIndex"`
+	RemoteID string
+}
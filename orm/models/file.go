@@ -0,0 +1,36 @@
+// Package models contains the gorm record types persisted to the sqlite database,
+// used to track upload progress and avoid re-uploading files that already succeeded.
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FileStatus represents where a queued file currently is in the upload pipeline.
+type FileStatus int
+
+const (
+	FilePending FileStatus = iota
+	FileUploading
+	FileSuccess
+	FileError
+)
+
+// File is the persisted record of a file that has been queued for upload. SHA256,
+// Size and MTime are used to detect when a file queued under a new path is
+// actually content identical to one that's already been uploaded.
+type File struct {
+	gorm.Model
+	Path   string `gorm:"uniqueIndex"`
+	Status FileStatus
+	SHA256 string `gorm:"index"`
+	Size   int64
+	MTime  time.Time
+
+	// ResumeOffset and UploadURL let api/library.Client.ResumableUpload continue a
+	// Library API upload that was interrupted, instead of starting over.
+	ResumeOffset int64
+	UploadURL    string
+}
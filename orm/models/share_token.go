@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ShareToken is an opaque, password-protected link to a shared album, created
+// through POST /api/albums/:id/share. The token itself grants no access; the
+// control server only reveals ShareURL once the caller redeems it with the
+// password used to create it. ExpiresAt is nil for tokens that never expire.
+type ShareToken struct {
+	gorm.Model
+	AlbumID      string
+	Token        string `gorm:"uniqueIndex"`
+	PasswordHash []byte
+	ShareURL     string
+	ExpiresAt    *time.Time
+}
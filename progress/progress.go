@@ -0,0 +1,234 @@
+// Package progress turns utils.ConcurrentUploader's event channels into live
+// terminal progress bars and the structured summary written by --stats-json.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+
+	"gphotosuploader/utils"
+)
+
+// maxPerWorkerBars caps how many per-worker bars a Reporter will draw; past
+// this, only the aggregate bar is shown, since a terminal full of bars stops
+// being readable.
+const maxPerWorkerBars = 8
+
+// Reporter is fed the events a caller reads from a ConcurrentUploader's
+// CompletedUploads, IgnoredUploads, Errors and Progress channels, drawing bars
+// (unless silenced) and building the Stats summary written at shutdown.
+type Reporter struct {
+	silent     bool
+	noProgress bool
+
+	mu         sync.Mutex
+	pool       *pb.Pool
+	total      *pb.ProgressBar
+	workerBars []*pb.ProgressBar
+
+	stats       Stats
+	latenciesMs []int64
+	started     time.Time
+}
+
+// Stats is the machine-readable summary written to --stats-json at shutdown.
+type Stats struct {
+	FilesUploaded  int            `json:"filesUploaded"`
+	FilesIgnored   int            `json:"filesIgnored"`
+	FilesFailed    int            `json:"filesFailed"`
+	TotalBytes     int64          `json:"totalBytes"`
+	DurationMs     int64          `json:"durationMs"`
+	AlbumCounts    map[string]int `json:"albumCounts"`
+	FailuresByType map[string]int `json:"failuresByType"`
+	LatencyP50Ms   int64          `json:"latencyP50Ms"`
+	LatencyP95Ms   int64          `json:"latencyP95Ms"`
+}
+
+// NewReporter builds a Reporter. maxConcurrent determines how many per-worker
+// bars are drawn alongside the aggregate one; when it exceeds maxPerWorkerBars,
+// no per-worker bars are drawn.
+// silent suppresses all output (bars and the final summary line); noProgress
+// suppresses only the bars, keeping the final summary line.
+func NewReporter(maxConcurrent int, silent, noProgress bool) *Reporter {
+	r := &Reporter{
+		noProgress: silent || noProgress,
+		started:    time.Now(),
+		stats: Stats{
+			AlbumCounts:    map[string]int{},
+			FailuresByType: map[string]int{},
+		},
+	}
+	r.silent = silent
+
+	if r.noProgress {
+		return r
+	}
+
+	r.total = pb.New64(0)
+	r.total.Set(pb.Bytes, true)
+	r.total.Set("prefix", "Total ")
+
+	bars := []*pb.ProgressBar{r.total}
+	if maxConcurrent <= maxPerWorkerBars {
+		r.workerBars = make([]*pb.ProgressBar, maxConcurrent)
+		for i := range r.workerBars {
+			bar := pb.New64(0)
+			bar.Set(pb.Bytes, true)
+			r.workerBars[i] = bar
+			bars = append(bars, bar)
+		}
+	}
+
+	r.pool = pb.NewPool(bars...)
+	r.pool.Start()
+	return r
+}
+
+// HandleProgress applies an event from ConcurrentUploader.Progress to the
+// aggregate bar and, if one is being shown, the event's worker bar.
+func (r *Reporter) HandleProgress(ev utils.ProgressEvent) {
+	if r.noProgress {
+		return
+	}
+
+	r.total.Add64(ev.BytesWritten)
+
+	if ev.WorkerID >= len(r.workerBars) {
+		return
+	}
+	bar := r.workerBars[ev.WorkerID]
+
+	if ev.Started {
+		bar.SetCurrent(0)
+		bar.SetTotal(ev.TotalBytes)
+		bar.Set("prefix", filepath.Base(ev.Path)+" ")
+		return
+	}
+	bar.Add64(ev.BytesWritten)
+}
+
+// RecordUploaded records a finished upload towards the Stats summary.
+func (r *Reporter) RecordUploaded(result utils.UploadResult, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.FilesUploaded++
+	r.stats.TotalBytes += result.Size
+	r.latenciesMs = append(r.latenciesMs, elapsed.Milliseconds())
+	if result.AlbumName != "" {
+		r.stats.AlbumCounts[result.AlbumName]++
+	}
+}
+
+// RecordIgnored records a file skipped as a duplicate or unsupported extension.
+func (r *Reporter) RecordIgnored() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.FilesIgnored++
+}
+
+// RecordFailed records a failed upload, grouped by a coarse error class so
+// --stats-json output stays useful without needing a taxonomy of error types.
+func (r *Reporter) RecordFailed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.FilesFailed++
+	r.stats.FailuresByType[classifyError(err)]++
+}
+
+func classifyError(err error) string {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "auth") || strings.Contains(msg, "credential") || strings.Contains(msg, "unauthorized"):
+		return "authentication"
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline"):
+		return "timeout"
+	case strings.Contains(msg, "network") || strings.Contains(msg, "connection") || strings.Contains(msg, "dial"):
+		return "network"
+	case strings.Contains(msg, "album"):
+		return "album"
+	case strings.Contains(msg, "hash"):
+		return "hashing"
+	default:
+		return "other"
+	}
+}
+
+// Finish stops the progress bars, if any are being shown.
+func (r *Reporter) Finish() {
+	if r.pool != nil {
+		r.pool.Stop()
+	}
+}
+
+// Summary returns a human-readable one-line summary, or "" when silent.
+func (r *Reporter) Summary() string {
+	if r.silent {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fmt.Sprintf("Done (%d files uploaded, %d files ignored, %d errors)",
+		r.stats.FilesUploaded, r.stats.FilesIgnored, r.stats.FilesFailed)
+}
+
+// Snapshot returns the current Stats, including live latency percentiles, safe
+// for a caller (e.g. GET /api/stats) to read and marshal concurrently with
+// further uploads.
+func (r *Reporter) Snapshot() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.AlbumCounts = copyCounts(r.stats.AlbumCounts)
+	stats.FailuresByType = copyCounts(r.stats.FailuresByType)
+	stats.DurationMs = time.Since(r.started).Milliseconds()
+	stats.LatencyP50Ms = percentile(r.latenciesMs, 50)
+	stats.LatencyP95Ms = percentile(r.latenciesMs, 95)
+	return stats
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	out := make(map[string]int, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// WriteStatsJSON writes a Snapshot of the run so far to path as JSON.
+func (r *Reporter) WriteStatsJSON(path string) error {
+	body, err := json.MarshalIndent(r.Snapshot(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't encode stats: %w", err)
+	}
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return fmt.Errorf("can't write %q: %w", path, err)
+	}
+	return nil
+}
+
+func percentile(samplesMs []int64, p int) int64 {
+	if len(samplesMs) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), samplesMs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,57 @@
+package progress
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	cases := []struct {
+		samples []int64
+		p       int
+		want    int64
+	}{
+		{nil, 50, 0},
+		{[]int64{100}, 50, 100},
+		{[]int64{100}, 95, 100},
+		{[]int64{10, 20, 30, 40, 50}, 50, 30},
+		{[]int64{10, 20, 30, 40, 50}, 95, 50},
+	}
+
+	for _, c := range cases {
+		got := percentile(c.samples, c.p)
+		if got != c.want {
+			t.Errorf("percentile(%v, %d) = %d, want %d", c.samples, c.p, got, c.want)
+		}
+	}
+}
+
+func TestPercentileDoesNotMutateInput(t *testing.T) {
+	samples := []int64{50, 10, 30}
+	percentile(samples, 50)
+	if samples[0] != 50 || samples[1] != 10 || samples[2] != 30 {
+		t.Fatalf("percentile mutated its input: %v", samples)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("401 unauthorized"), "authentication"},
+		{errors.New("invalid credential"), "authentication"},
+		{errors.New("context deadline exceeded"), "timeout"},
+		{fmt.Errorf("dial tcp: connection refused"), "network"},
+		{errors.New("can't create album %q"), "album"},
+		{errors.New("can't hash file"), "hashing"},
+		{errors.New("disk full"), "other"},
+	}
+
+	for _, c := range cases {
+		if got := classifyError(c.err); got != c.want {
+			t.Errorf("classifyError(%q) = %q, want %q", c.err, got, c.want)
+		}
+	}
+}
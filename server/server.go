@@ -0,0 +1,335 @@
+// Package server exposes an optional HTTP control API for a running upload
+// session: inspecting the queue, enqueuing or retrying files, live run stats,
+// and issuing password-protected share links for albums. It's enabled with
+// --listen and backed by the same orm singleton and ConcurrentUploader the CLI
+// itself uses, turning a one-shot run into something operators can poll.
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"gphotosuploader/orm"
+	"gphotosuploader/orm/models"
+	"gphotosuploader/progress"
+	"gphotosuploader/storage"
+	"gphotosuploader/utils"
+)
+
+// Server is the HTTP control API described in the package doc. Build one with
+// New and run it with ListenAndServe.
+type Server struct {
+	uploader *utils.ConcurrentUploader
+	backend  storage.Backend
+	reporter *progress.Reporter
+	token    string
+	mux      *http.ServeMux
+}
+
+// New builds a Server that enqueues uploads through uploader, creates share
+// links through backend, and reports live stats from reporter. token must be
+// presented as "Authorization: Bearer <token>" on every endpoint except
+// /api/share/redeem, which is the deliberately public flow a recipient uses
+// with just the per-share password.
+func New(uploader *utils.ConcurrentUploader, backend storage.Backend, reporter *progress.Reporter, token string) *Server {
+	s := &Server{uploader: uploader, backend: backend, reporter: reporter, token: token, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/api/files", s.handleFiles)
+	s.mux.HandleFunc("/api/upload", s.handleUpload)
+	s.mux.HandleFunc("/api/retry/", s.handleRetry)
+	s.mux.HandleFunc("/api/stats", s.handleStats)
+	s.mux.HandleFunc("/api/albums/", s.handleAlbumShare)
+	s.mux.HandleFunc("/api/share/redeem", s.handleShareRedeem)
+	return s
+}
+
+// ListenAndServe starts the control API on addr, blocking until it exits. A
+// bare ":port" address (no host) is bound to the loopback interface only; pass
+// an explicit host (e.g. "0.0.0.0:8080") to listen on all interfaces instead.
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(loopbackAddr(addr), s.mux)
+}
+
+func loopbackAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return "127.0.0.1:" + port
+}
+
+// requireToken enforces the bearer token, writing a 401 and returning false if
+// it's missing or wrong.
+func (s *Server) requireToken(w http.ResponseWriter, r *http.Request) bool {
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// GET /api/files?status=&page=&pageSize= lists queued files, most recent last,
+// optionally filtered by status (one of pending, uploading, success, error).
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireToken(w, r) {
+		return
+	}
+
+	page := queryInt(r, "page", 1)
+	pageSize := queryInt(r, "pageSize", 50)
+
+	query := orm.GetInstance().Connection.Model(&models.File{})
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		status, err := parseStatus(raw)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var files []models.File
+	if err := query.Order("id").Offset((page - 1) * pageSize).Limit(pageSize).Find(&files).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, files)
+}
+
+// POST /api/upload {"path": "..."} enqueues path the same way a CLI --upload
+// argument would.
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireToken(w, r) {
+		return
+	}
+
+	var body struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Path == "" {
+		http.Error(w, `body must be {"path": "..."}`, http.StatusBadRequest)
+		return
+	}
+
+	s.uploader.EnqueueUpload(body.Path)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// POST /api/retry/:id re-enqueues the file with that database ID through
+// EnqueueUpload, whatever its current status; EnqueueUpload still ignores it if
+// its content has already been uploaded successfully.
+func (s *Server) handleRetry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireToken(w, r) {
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/retry/"))
+	if err != nil {
+		http.Error(w, "id must be numeric", http.StatusBadRequest)
+		return
+	}
+
+	var file models.File
+	if err := orm.GetInstance().Connection.First(&file, id).Error; err != nil {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return
+	}
+
+	s.uploader.EnqueueUpload(file.Path)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GET /api/stats returns the same summary --stats-json writes at shutdown, live.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireToken(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.reporter.Snapshot())
+}
+
+// POST /api/albums/:id/share {"password": "...", "expiresInSeconds": 0} creates
+// a password-protected ShareToken for the album and returns its opaque token;
+// the password and underlying share URL are never returned here. Redeem the
+// token with the same password via POST /api/share/redeem.
+func (s *Server) handleAlbumShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireToken(w, r) {
+		return
+	}
+
+	albumID, ok := parseAlbumSharePath(r.URL.Path)
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		Password         string `json:"password"`
+		ExpiresInSeconds int64  `json:"expiresInSeconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Password == "" {
+		http.Error(w, `body must be {"password": "...", "expiresInSeconds": 0}`, http.StatusBadRequest)
+		return
+	}
+
+	shareURL, err := s.backend.Share(albumID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	share := models.ShareToken{
+		AlbumID:      albumID,
+		Token:        token,
+		PasswordHash: passwordHash,
+		ShareURL:     shareURL,
+	}
+	if body.ExpiresInSeconds > 0 {
+		expires := time.Now().Add(time.Duration(body.ExpiresInSeconds) * time.Second)
+		share.ExpiresAt = &expires
+	}
+
+	if err := orm.GetInstance().Connection.Create(&share).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, struct {
+		Token string `json:"token"`
+	}{Token: token})
+}
+
+// POST /api/share/redeem {"token": "...", "password": "..."} verifies password
+// against the token's bcrypt hash and, on success, returns the pre-signed
+// ShareURL it was issued for.
+func (s *Server) handleShareRedeem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Token == "" || body.Password == "" {
+		http.Error(w, `body must be {"token": "...", "password": "..."}`, http.StatusBadRequest)
+		return
+	}
+
+	var share models.ShareToken
+	if err := orm.GetInstance().Connection.Where(&models.ShareToken{Token: body.Token}).First(&share).Error; err != nil {
+		http.Error(w, "share not found", http.StatusNotFound)
+		return
+	}
+
+	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
+		http.Error(w, "share has expired", http.StatusGone)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword(share.PasswordHash, []byte(body.Password)); err != nil {
+		http.Error(w, "wrong password", http.StatusForbidden)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		ShareURL string `json:"shareUrl"`
+	}{ShareURL: share.ShareURL})
+}
+
+func parseAlbumSharePath(path string) (albumID string, ok bool) {
+	const prefix = "/api/albums/"
+	const suffix = "/share"
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		return "", false
+	}
+	albumID = strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	if albumID == "" {
+		return "", false
+	}
+	return albumID, true
+}
+
+func parseStatus(raw string) (models.FileStatus, error) {
+	switch raw {
+	case "pending":
+		return models.FilePending, nil
+	case "uploading":
+		return models.FileUploading, nil
+	case "success":
+		return models.FileSuccess, nil
+	case "error":
+		return models.FileError, nil
+	default:
+		return 0, fmt.Errorf("unknown status %q", raw)
+	}
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("can't generate share token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func queryInt(r *http.Request, name string, fallback int) int {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return fallback
+	}
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gphotosuploader/progress"
+	"gphotosuploader/storage"
+	"gphotosuploader/utils"
+
+	_ "gphotosuploader/storage/localfs"
+)
+
+const testToken = "test-token"
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	backend, err := storage.New("localfs", t.TempDir())
+	if err != nil {
+		t.Fatalf("can't create localfs backend: %v", err)
+	}
+	uploader, err := utils.NewUploader(backend, "", "", "", "", 1)
+	if err != nil {
+		t.Fatalf("can't create uploader: %v", err)
+	}
+	reporter := progress.NewReporter(1, true, true)
+
+	return New(uploader, backend, reporter, testToken)
+}
+
+// uploadableFile creates a real file so EnqueueUpload's hashing step succeeds
+// and the upload is handed off to the (buffered) queue channel instead of
+// blocking the request trying to report a hashing error.
+func uploadableFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("fake jpeg bytes"), 0644); err != nil {
+		t.Fatalf("can't write fixture: %v", err)
+	}
+	return path
+}
+
+func TestHandleUploadRequiresToken(t *testing.T) {
+	s := newTestServer(t)
+
+	body := fmt.Sprintf(`{"path":%q}`, uploadableFile(t))
+	req := httptest.NewRequest(http.MethodPost, "/api/upload", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d (missing token)", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestHandleUploadWithValidToken(t *testing.T) {
+	s := newTestServer(t)
+
+	body := fmt.Sprintf(`{"path":%q}`, uploadableFile(t))
+	req := httptest.NewRequest(http.MethodPost, "/api/upload", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+}
+
+func TestHandleUploadWithWrongToken(t *testing.T) {
+	s := newTestServer(t)
+
+	body := fmt.Sprintf(`{"path":%q}`, uploadableFile(t))
+	req := httptest.NewRequest(http.MethodPost, "/api/upload", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer nope")
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d (wrong token)", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestHandleFilesRequiresToken(t *testing.T) {
+	s := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files", nil)
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d (missing token)", rec.Code, http.StatusUnauthorized)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/files", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec = httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestHandleStatsRequiresToken(t *testing.T) {
+	s := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d (missing token)", rec.Code, http.StatusUnauthorized)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec = httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestLoopbackAddr(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{":8080", "127.0.0.1:8080"},
+		{"0.0.0.0:8080", "0.0.0.0:8080"},
+		{"example.com:8080", "example.com:8080"},
+	}
+
+	for _, c := range cases {
+		if got := loopbackAddr(c.in); got != c.want {
+			t.Errorf("loopbackAddr(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package storage defines the Backend abstraction that utils.ConcurrentUploader
+// uploads through, plus a registry so new destinations can be added without
+// forking the uploader's queuing logic.
+package storage
+
+import (
+	"fmt"
+
+	"gphotosuploader/orm/models"
+)
+
+// Backend is anything that can receive uploaded files and organize them into
+// albums. ConcurrentUploader depends only on this interface, not on Google Photos
+// directly, so alternate destinations (a local mirror, S3, ...) can be swapped in.
+type Backend interface {
+	// Upload sends file's contents to the backend, updating file in place with
+	// any resume state (offset, upload URL, ...) needed if the run is interrupted.
+	// onProgress, if non-nil, is called with the number of bytes written on every
+	// chunk so a caller can report live upload progress; it may be called from
+	// whatever goroutine is driving the transfer.
+	Upload(file *models.File, onProgress func(bytesWritten int64)) error
+	// Exists reports whether file has already been fully stored by this backend.
+	Exists(file *models.File) (bool, error)
+	// Delete removes a previously uploaded file from the backend.
+	Delete(file *models.File) error
+	// CreateAlbum creates the album identified by name and returns its ID. Whether
+	// an existing album of that name is reused depends on the backend: localfs
+	// reuses the directory, the oauth backend creates a new album on every call.
+	CreateAlbum(name string) (albumID string, err error)
+	// AddToAlbum adds an already-uploaded file to the given album, reporting
+	// progress the same way as Upload.
+	AddToAlbum(albumID string, file *models.File, onProgress func(bytesWritten int64)) error
+	// Share makes albumID accessible to anyone with the returned URL.
+	Share(albumID string) (shareURL string, err error)
+}
+
+// Factory builds a Backend from the raw --backend-config string passed on the
+// command line; each backend documents its own config format.
+type Factory func(config string) (Backend, error)
+
+var registry = map[string]Factory{}
+
+// Register makes a backend factory available under name for --backend to select.
+// Called from each backend package's init(), the same way database/sql drivers
+// register themselves.
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// New builds the backend registered under name, passing it config.
+func New(name, config string) (Backend, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+	return factory(config)
+}
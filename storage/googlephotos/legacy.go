@@ -0,0 +1,172 @@
+// Package googlephotos implements storage.Backend for Google Photos, with two
+// variants: "legacy", which reuses the cookie + at-token scraping flow in package
+// auth, and "oauth", which uses the Google Photos Library API in package
+// api/library. Both register themselves with the storage registry so main.go can
+// select one with --backend.
+package googlephotos
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tebeka/selenium"
+
+	"gphotosuploader/api"
+	"gphotosuploader/auth"
+	"gphotosuploader/orm/models"
+	"gphotosuploader/storage"
+)
+
+func init() {
+	storage.Register("legacy", newLegacyBackend)
+}
+
+// newLegacyBackend builds the cookie/at-token backend. config is the path to the
+// auth file, defaulting to "auth.json" when empty. If it doesn't contain valid
+// credentials yet, the user is walked through the WebDriver sign-in wizard.
+func newLegacyBackend(config string) (storage.Backend, error) {
+	authFile := config
+	if authFile == "" {
+		authFile = "auth.json"
+	}
+
+	credentials, err := loadOrCreateCookieCredentials(authFile)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Getting a new At token ...")
+	token, err := api.NewAtTokenScraper(*credentials).ScrapeNewAtToken()
+	if err != nil {
+		return nil, fmt.Errorf("can't scrape a new At token: %w", err)
+	}
+	credentials.RuntimeParameters.AtToken = token
+
+	return &LegacyBackend{credentials: *credentials}, nil
+}
+
+func loadOrCreateCookieCredentials(authFile string) (*auth.CookieCredentials, error) {
+	credentials, err := auth.NewCookieCredentialsFromFile(authFile)
+	if err == nil {
+		validity, err := credentials.CheckCredentials()
+		if err != nil {
+			return nil, fmt.Errorf("can't check validity of credentials: %w", err)
+		}
+		if validity.Valid {
+			return credentials, nil
+		}
+		log.Printf("Credentials are not valid! %v\n", validity.Reason)
+	} else {
+		log.Printf("Can't use %q as auth file (%v)\n", authFile, err)
+	}
+
+	fmt.Println("The uploader can't continue without valid authentication tokens ...")
+	fmt.Println("Would you like to run the WebDriver CookieCredentials Wizard ? [Yes/No]")
+	fmt.Println("(If you don't know what it is, refer to the README)")
+
+	var answer string
+	fmt.Scanln(&answer)
+	if !(len(answer) > 0 && strings.ToLower(answer)[0] == 'y') {
+		return nil, fmt.Errorf("can't continue without valid authentication tokens")
+	}
+
+	credentials, err = startWebDriverCookieCredentialsWizard()
+	if err != nil {
+		return nil, fmt.Errorf("can't complete the login wizard: %w", err)
+	}
+	if err := credentials.SerializeToFile(authFile); err != nil {
+		log.Printf("Can't save auth file %q: %v\n", authFile, err)
+	}
+	return credentials, nil
+}
+
+const googlePhotosSignInURL = "https://photos.google.com/"
+
+// startWebDriverCookieCredentialsWizard drives a real browser through the Google
+// sign-in flow with Selenium and scrapes the resulting session cookies into a
+// CookieCredentials, for users on the legacy backend who don't have an auth file
+// yet. Users on the oauth backend go through auth/oauth.Authenticate instead.
+func startWebDriverCookieCredentialsWizard() (*auth.CookieCredentials, error) {
+	caps := selenium.Capabilities{"browserName": "chrome"}
+	wd, err := selenium.NewRemote(caps, "")
+	if err != nil {
+		return nil, fmt.Errorf("can't start the WebDriver session: %w", err)
+	}
+	defer wd.Quit()
+
+	if err := wd.Get(googlePhotosSignInURL); err != nil {
+		return nil, fmt.Errorf("can't open the Google sign-in page: %w", err)
+	}
+
+	fmt.Println("Please sign in to your Google account in the opened browser window, then press Enter here ...")
+	fmt.Scanln()
+
+	cookies, err := wd.GetCookies()
+	if err != nil {
+		return nil, fmt.Errorf("can't read cookies from the browser session: %w", err)
+	}
+
+	httpCookies := make([]*http.Cookie, len(cookies))
+	for i, c := range cookies {
+		httpCookies[i] = &http.Cookie{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path}
+	}
+
+	return auth.NewCookieCredentials(httpCookies)
+}
+
+// LegacyBackend implements storage.Backend using the scraped cookie session and at
+// token; only Upload is fully supported, matching what the scraping flow has ever
+// provided.
+type LegacyBackend struct {
+	credentials auth.CookieCredentials
+}
+
+const legacyUploadURL = "https://photos.google.com/_/upload/photos/resumable?authkey"
+
+func (b *LegacyBackend) Upload(file *models.File, onProgress func(bytesWritten int64)) error {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return fmt.Errorf("can't open %q: %w", file.Path, err)
+	}
+	defer f.Close()
+
+	req, err := http.NewRequest("POST", legacyUploadURL, storage.ProgressReader(f, onProgress))
+	if err != nil {
+		return fmt.Errorf("can't build legacy upload request: %w", err)
+	}
+	req.Header.Set("X-Goog-AT", b.credentials.RuntimeParameters.AtToken)
+
+	res, err := b.credentials.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("legacy upload failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("legacy upload returned status %s", res.Status)
+	}
+	return nil
+}
+
+func (b *LegacyBackend) Exists(file *models.File) (bool, error) {
+	return false, fmt.Errorf("legacy backend can't check remote existence, rely on the local sqlite record instead")
+}
+
+func (b *LegacyBackend) Delete(file *models.File) error {
+	return fmt.Errorf("legacy backend doesn't support deleting uploaded files")
+}
+
+func (b *LegacyBackend) CreateAlbum(name string) (string, error) {
+	return "", fmt.Errorf("legacy backend doesn't support album management, run without --album, --albumName and --albumTemplate")
+}
+
+func (b *LegacyBackend) Share(albumID string) (string, error) {
+	return "", fmt.Errorf("legacy backend doesn't support creating share links, use --backend=oauth instead")
+}
+
+func (b *LegacyBackend) AddToAlbum(albumID string, file *models.File, onProgress func(bytesWritten int64)) error {
+	return fmt.Errorf("legacy backend doesn't support album management, run without --album, --albumName and --albumTemplate")
+}
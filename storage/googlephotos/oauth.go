@@ -0,0 +1,88 @@
+package googlephotos
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gphotosuploader/api/library"
+	"gphotosuploader/auth/oauth"
+	"gphotosuploader/orm/models"
+	"gphotosuploader/storage"
+)
+
+func init() {
+	storage.Register("oauth", newOAuthBackend)
+}
+
+// newOAuthBackend builds the Library API backend. config is a comma-separated
+// list of key=value pairs: clientID and clientSecret (required) and authFile
+// (optional, defaults to "auth.json"), e.g. "clientID=...,clientSecret=...". If
+// authFile doesn't contain a usable token yet, the browser authorization flow
+// runs to create one.
+func newOAuthBackend(config string) (storage.Backend, error) {
+	values := parseConfig(config)
+
+	authFile := values["authFile"]
+	if authFile == "" {
+		authFile = "auth.json"
+	}
+
+	credentials, err := oauth.NewCredentialsFromFile(values["clientID"], values["clientSecret"], authFile)
+	if err != nil {
+		log.Println("No valid OAuth2 credentials on disk, starting the browser authorization flow ...")
+		credentials, err = oauth.Authenticate(values["clientID"], values["clientSecret"], authFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't complete OAuth2 authorization: %w", err)
+		}
+	}
+
+	return &OAuthBackend{client: library.NewClient(credentials.HTTPClient())}, nil
+}
+
+func parseConfig(config string) map[string]string {
+	values := map[string]string{}
+	for _, pair := range strings.Split(config, ",") {
+		if key, value, found := strings.Cut(pair, "="); found {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// OAuthBackend implements storage.Backend against the Google Photos Library API.
+type OAuthBackend struct {
+	client *library.Client
+}
+
+func (b *OAuthBackend) Upload(file *models.File, onProgress func(bytesWritten int64)) error {
+	token, err := b.client.ResumableUpload(file.Path, file, onProgress)
+	if err != nil {
+		return err
+	}
+	return b.client.CreateMediaItem(token, "")
+}
+
+func (b *OAuthBackend) Exists(file *models.File) (bool, error) {
+	return false, fmt.Errorf("the Library API doesn't expose a lookup by content, rely on the local sqlite record instead")
+}
+
+func (b *OAuthBackend) Delete(file *models.File) error {
+	return fmt.Errorf("the Library API doesn't support deleting media items")
+}
+
+func (b *OAuthBackend) CreateAlbum(name string) (string, error) {
+	return b.client.CreateAlbum(name)
+}
+
+func (b *OAuthBackend) Share(albumID string) (string, error) {
+	return b.client.ShareAlbum(albumID)
+}
+
+func (b *OAuthBackend) AddToAlbum(albumID string, file *models.File, onProgress func(bytesWritten int64)) error {
+	token, err := b.client.ResumableUpload(file.Path, file, onProgress)
+	if err != nil {
+		return err
+	}
+	return b.client.CreateMediaItem(token, albumID)
+}
@@ -0,0 +1,169 @@
+// Package localfs implements storage.Backend by mirroring uploaded files into a
+// local directory tree instead of Google Photos, for dry runs, local backups, or
+// mirroring to a destination of the user's choosing.
+package localfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gphotosuploader/orm/models"
+	"gphotosuploader/storage"
+)
+
+func init() {
+	storage.Register("localfs", newBackend)
+}
+
+// newBackend builds a localfs Backend rooted at config, the mirror directory;
+// config defaults to "./mirror" when empty.
+func newBackend(config string) (storage.Backend, error) {
+	root := config
+	if root == "" {
+		root = "./mirror"
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("can't create mirror root %q: %w", root, err)
+	}
+	return &Backend{root: root}, nil
+}
+
+// Backend mirrors files into <root>/<album>/<yyyy>/<mm>/<sha256-prefix>/<basename>.
+type Backend struct {
+	root string
+}
+
+const defaultAlbum = "unsorted"
+
+func (b *Backend) Upload(file *models.File, onProgress func(bytesWritten int64)) error {
+	dest, err := b.destinationPath(defaultAlbum, file)
+	if err != nil {
+		return err
+	}
+	return copyInto(file.Path, dest, onProgress)
+}
+
+// Exists only looks for file under the default "unsorted" album; a copy placed
+// in another album with AddToAlbum is not found.
+func (b *Backend) Exists(file *models.File) (bool, error) {
+	dest, err := b.destinationPath(defaultAlbum, file)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(dest)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete only removes file from the default "unsorted" album; copies placed in
+// other albums with AddToAlbum are left in place.
+func (b *Backend) Delete(file *models.File) error {
+	dest, err := b.destinationPath(defaultAlbum, file)
+	if err != nil {
+		return err
+	}
+	return os.Remove(dest)
+}
+
+// CreateAlbum just creates the album subdirectory and uses its name as the ID.
+func (b *Backend) CreateAlbum(name string) (albumID string, err error) {
+	if err := validateAlbumComponent(name); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(b.root, name), 0755); err != nil {
+		return "", fmt.Errorf("can't create album directory %q: %w", name, err)
+	}
+	return name, nil
+}
+
+// Share returns a file:// URL to the album directory; there's no real access
+// control on a local mirror, so this is only useful for testing the share flow
+// end-to-end without an oauth backend.
+func (b *Backend) Share(albumID string) (string, error) {
+	abs, err := filepath.Abs(filepath.Join(b.root, albumID))
+	if err != nil {
+		return "", fmt.Errorf("can't resolve album directory %q: %w", albumID, err)
+	}
+	return "file://" + abs, nil
+}
+
+func (b *Backend) AddToAlbum(albumID string, file *models.File, onProgress func(bytesWritten int64)) error {
+	dest, err := b.destinationPath(albumID, file)
+	if err != nil {
+		return err
+	}
+	return copyInto(file.Path, dest, onProgress)
+}
+
+func (b *Backend) destinationPath(album string, file *models.File) (string, error) {
+	if err := validateAlbumComponent(album); err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return "", fmt.Errorf("can't stat %q: %w", file.Path, err)
+	}
+
+	return filepath.Join(b.root, album,
+		fmt.Sprintf("%04d", info.ModTime().Year()),
+		fmt.Sprintf("%02d", info.ModTime().Month()),
+		file.SHA256[:8],
+		filepath.Base(file.Path),
+	), nil
+}
+
+// validateAlbumComponent rejects an album name that isn't safe to use as a
+// single path component under b.root. Callers normally already route album
+// names through AlbumResolver, which applies the same check, but an album
+// name derived from a file's EXIF tags is attacker-controlled, so this
+// backend can't assume it arrived pre-validated.
+func validateAlbumComponent(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("album %q isn't a valid directory name", name)
+	}
+	if filepath.Base(name) != name {
+		return fmt.Errorf("album %q can't contain a path separator", name)
+	}
+	return nil
+}
+
+// copyInto hardlinks src to dest when possible, falling back to a full copy
+// across filesystem boundaries, creating any missing parent directories first.
+// A hardlink is reported as complete in a single onProgress call since no bytes
+// actually move; a real copy reports as it streams.
+func copyInto(src, dest string, onProgress func(bytesWritten int64)) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("can't create %q: %w", filepath.Dir(dest), err)
+	}
+
+	if err := os.Link(src, dest); err == nil {
+		if onProgress != nil {
+			if info, err := os.Stat(dest); err == nil {
+				onProgress(info.Size())
+			}
+		}
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("can't open %q: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("can't create %q: %w", dest, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, storage.ProgressReader(in, onProgress)); err != nil {
+		return fmt.Errorf("can't copy %q to %q: %w", src, dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package storage
+
+import "io"
+
+// ProgressReader wraps r so that onProgress is called with the number of bytes
+// read on every call to Read, letting a Backend report live upload progress
+// without buffering the file it's streaming.
+func ProgressReader(r io.Reader, onProgress func(bytesRead int64)) io.Reader {
+	if onProgress == nil {
+		return r
+	}
+	return &progressReader{r: r, onProgress: onProgress}
+}
+
+type progressReader struct {
+	r          io.Reader
+	onProgress func(bytesRead int64)
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	if n > 0 {
+		p.onProgress(int64(n))
+	}
+	return n, err
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gphotosuploader/exif"
+	"gphotosuploader/orm"
+	"gphotosuploader/orm/models"
+	"gphotosuploader/storage"
+)
+
+// AlbumResolver decides which album a queued file belongs to. With an
+// --albumTemplate configured it's evaluated per file against the file's EXIF
+// metadata (falling back to mtime when there's none), resolving to a single fixed
+// album otherwise. Album IDs are cached in orm/models.Album so each album name is
+// only created once against the backend.
+type AlbumResolver struct {
+	backend        storage.Backend
+	template       *template.Template
+	defaultAlbum   string
+	fixedAlbumID   string
+	fixedAlbumName string
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// NewAlbumResolver builds an AlbumResolver. When albumTemplate is empty, it
+// behaves like the single static album gphotosuploader always supported: if
+// albumId is set it's used as-is, otherwise albumName (if set) is created once up
+// front. When albumTemplate is set, it takes priority and every file is routed
+// independently; defaultAlbum is used whenever the template fails or evaluates
+// to an empty name for a file (e.g. {{.Camera}} on a file without EXIF).
+func NewAlbumResolver(backend storage.Backend, albumId, albumName, albumTemplate, defaultAlbum string) (*AlbumResolver, error) {
+	r := &AlbumResolver{backend: backend, defaultAlbum: defaultAlbum, cache: map[string]string{}}
+
+	if albumTemplate != "" {
+		tmpl, err := template.New("albumTemplate").Parse(albumTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse --albumTemplate %q: %w", albumTemplate, err)
+		}
+		r.template = tmpl
+		return r, nil
+	}
+
+	if albumId == "" && albumName != "" {
+		id, err := backend.CreateAlbum(albumName)
+		if err != nil {
+			return nil, fmt.Errorf("can't create album %q: %w", albumName, err)
+		}
+		albumId = id
+	}
+	r.fixedAlbumID = albumId
+	r.fixedAlbumName = albumName
+	return r, nil
+}
+
+// Resolve returns the album ID (and its name, for reporting) qf should be added
+// to, or "" to upload it without placing it in any album.
+func (r *AlbumResolver) Resolve(qf queuedFile) (albumID, albumName string, err error) {
+	if r.template == nil {
+		return r.fixedAlbumID, r.fixedAlbumName, nil
+	}
+
+	// meta is the zero Metadata when Read fails (no EXIF segment, or a format
+	// goexif doesn't parse); Template still falls back to qf.mtime for the date
+	// fields in that case, so date-based templates keep working either way.
+	meta, _ := exif.Read(qf.path)
+
+	name := r.defaultAlbum
+	var buf strings.Builder
+	if err := r.template.Execute(&buf, meta.Template(qf.mtime)); err == nil && buf.String() != "" {
+		name = buf.String()
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("--albumTemplate gave no album name for %q and no --defaultAlbum is configured", qf.path)
+	}
+	if err := validateAlbumName(name); err != nil {
+		return "", "", fmt.Errorf("%q resolved to an invalid album name: %w", qf.path, err)
+	}
+
+	id, err := r.albumID(name)
+	return id, name, err
+}
+
+// validateAlbumName rejects album names that could escape a backend's storage
+// root when used as a path component (e.g. a localfs mirror). --albumTemplate
+// values can come straight from a file's EXIF tags, which is attacker-
+// controlled metadata, so this isn't just a hygiene check.
+func validateAlbumName(name string) error {
+	if name == "." || name == ".." {
+		return fmt.Errorf("album name %q isn't allowed", name)
+	}
+	if strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("album name %q can't contain a path separator", name)
+	}
+	return nil
+}
+
+func (r *AlbumResolver) albumID(name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.cache[name]; ok {
+		return id, nil
+	}
+
+	var album models.Album
+	orm.GetInstance().Connection.Where(&models.Album{Name: name}).First(&album)
+	if album.ID != 0 {
+		r.cache[name] = album.RemoteID
+		return album.RemoteID, nil
+	}
+
+	id, err := r.backend.CreateAlbum(name)
+	if err != nil {
+		return "", fmt.Errorf("can't create album %q: %w", name, err)
+	}
+
+	orm.GetInstance().Connection.Create(&models.Album{Name: name, RemoteID: id})
+	r.cache[name] = id
+	return id, nil
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestValidateAlbumName(t *testing.T) {
+	cases := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"2026-03", false},
+		{"Pixel 7 Pro", false},
+		{"..", true},
+		{".", true},
+		{"../../etc", true},
+		{"a/b", true},
+		{`a\b`, true},
+	}
+
+	for _, c := range cases {
+		err := validateAlbumName(c.name)
+		if c.wantErr && err == nil {
+			t.Errorf("validateAlbumName(%q) = nil, want an error", c.name)
+		}
+		if !c.wantErr && err != nil {
+			t.Errorf("validateAlbumName(%q) = %v, want nil", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package utils provides the queueing, concurrency, hashing and album-routing
+// glue that sits between main's CLI layer and the storage.Backend it uploads to.
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FilesToUpload collects repeated -upload flag values into a slice.
+type FilesToUpload []string
+
+func (f *FilesToUpload) String() string { return strings.Join(*f, ",") }
+
+func (f *FilesToUpload) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// DirectoriesToWatch collects repeated -watch flag values into a slice.
+type DirectoriesToWatch []string
+
+func (d *DirectoriesToWatch) String() string { return strings.Join(*d, ",") }
+
+func (d *DirectoriesToWatch) Set(value string) error {
+	*d = append(*d, value)
+	return nil
+}
+
+// PatternsToIgnore collects repeated -ignore flag values, compiling each into a
+// regexp so checkIgnore can match candidate paths against them.
+type PatternsToIgnore []*regexp.Regexp
+
+func (p *PatternsToIgnore) String() string {
+	parts := make([]string, len(*p))
+	for i, re := range *p {
+		parts[i] = re.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (p *PatternsToIgnore) Set(value string) error {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, re)
+	return nil
+}
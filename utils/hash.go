@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+// HashFile streams path through sha256 and returns its hex digest, size and
+// modification time, without holding the whole file in memory. It's shared
+// between ConcurrentUploader's duplicate-content check and the --rehash backfill.
+func HashFile(path string) (sha256Hex string, size int64, mtime time.Time, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, time.Time{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, time.Time{}, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", 0, time.Time{}, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), info.Size(), info.ModTime(), nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("some bytes"), 0644); err != nil {
+		t.Fatalf("can't write fixture: %v", err)
+	}
+
+	sum, size, mtime, err := HashFile(path)
+	if err != nil {
+		t.Fatalf("HashFile returned an error: %v", err)
+	}
+
+	if sum == "" {
+		t.Fatal("HashFile returned an empty digest")
+	}
+	if size != int64(len("some bytes")) {
+		t.Fatalf("size = %d, want %d", size, len("some bytes"))
+	}
+	if time.Since(mtime) > time.Minute {
+		t.Fatalf("mtime %v looks stale for a file just written", mtime)
+	}
+
+	// Hashing the same contents again must produce the same digest.
+	sum2, _, _, err := HashFile(path)
+	if err != nil {
+		t.Fatalf("second HashFile returned an error: %v", err)
+	}
+	if sum2 != sum {
+		t.Fatalf("HashFile isn't deterministic: got %q then %q", sum, sum2)
+	}
+}
+
+func TestHashFileMissing(t *testing.T) {
+	_, _, _, err := HashFile(filepath.Join(t.TempDir(), "does-not-exist.jpg"))
+	if err == nil {
+		t.Fatal("expected an error hashing a missing file")
+	}
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gphotosuploader/orm"
+	"gphotosuploader/orm/models"
+	"gphotosuploader/storage"
+)
+
+var mediaExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".heic": true,
+	".mp4": true, ".mov": true, ".avi": true,
+}
+
+// ConcurrentUploader queues files for upload and drives up to maxConcurrent of
+// them through the configured storage.Backend at a time, reporting progress on
+// its channels.
+type ConcurrentUploader struct {
+	backend       storage.Backend
+	albums        *AlbumResolver
+	maxConcurrent int
+	queue         chan queuedFile
+	wg            sync.WaitGroup
+
+	CompletedUploads chan UploadResult
+	IgnoredUploads   chan string
+	Errors           chan error
+	// Progress carries byte-level updates for whichever files are currently
+	// uploading, keyed by WorkerID, so a caller can drive one bar per worker slot
+	// instead of only refreshing on file completion.
+	Progress chan ProgressEvent
+}
+
+// UploadResult describes a file that finished uploading successfully.
+type UploadResult struct {
+	Path      string
+	AlbumName string
+	Size      int64
+}
+
+// ProgressEvent reports incremental upload progress for the file currently
+// occupying worker slot WorkerID. Started is set on the single event marking
+// the beginning of a new file's upload (so a consumer can reset/retitle a
+// per-worker bar); subsequent events for the same file carry the bytes written
+// by the latest chunk.
+type ProgressEvent struct {
+	WorkerID     int
+	Path         string
+	Started      bool
+	BytesWritten int64
+	TotalBytes   int64
+}
+
+// queuedFile carries the content hash computed by EnqueueUpload through to
+// upload, so each file is only hashed once.
+type queuedFile struct {
+	path   string
+	sha256 string
+	size   int64
+	mtime  time.Time
+}
+
+// NewUploader builds a ConcurrentUploader that uploads through backend, routing
+// each file to an album via an AlbumResolver built from the same parameters; see
+// NewAlbumResolver for how albumId/albumName/albumTemplate/defaultAlbum interact.
+func NewUploader(backend storage.Backend, albumId, albumName, albumTemplate, defaultAlbum string, maxConcurrent int) (*ConcurrentUploader, error) {
+	albums, err := NewAlbumResolver(backend, albumId, albumName, albumTemplate, defaultAlbum)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &ConcurrentUploader{
+		backend:          backend,
+		albums:           albums,
+		maxConcurrent:    maxConcurrent,
+		queue:            make(chan queuedFile, 1024),
+		CompletedUploads: make(chan UploadResult),
+		IgnoredUploads:   make(chan string),
+		Errors:           make(chan error),
+		Progress:         make(chan ProgressEvent, 64),
+	}
+	for i := 0; i < maxConcurrent; i++ {
+		go u.worker(i)
+	}
+	return u, nil
+}
+
+func (u *ConcurrentUploader) worker(id int) {
+	for qf := range u.queue {
+		u.upload(qf, id)
+		u.wg.Done()
+	}
+}
+
+// EnqueueUpload schedules path for upload unless it isn't a recognised
+// image/video extension, or its content hash matches a file that's already been
+// uploaded successfully, even under a different path (a rename, a move between
+// watched directories, or a re-download of the same photo).
+func (u *ConcurrentUploader) EnqueueUpload(path string) {
+	if !mediaExtensions[strings.ToLower(filepath.Ext(path))] {
+		u.IgnoredUploads <- path
+		return
+	}
+
+	sum, size, mtime, err := HashFile(path)
+	if err != nil {
+		u.Errors <- fmt.Errorf("can't hash %q: %w", path, err)
+		return
+	}
+
+	var existing models.File
+	orm.GetInstance().Connection.Where(&models.File{SHA256: sum, Status: models.FileSuccess}).First(&existing)
+	if existing.ID != 0 {
+		u.IgnoredUploads <- path
+		return
+	}
+
+	u.wg.Add(1)
+	u.queue <- queuedFile{path: path, sha256: sum, size: size, mtime: mtime}
+}
+
+func (u *ConcurrentUploader) upload(qf queuedFile, workerID int) {
+	u.Progress <- ProgressEvent{WorkerID: workerID, Path: qf.path, TotalBytes: qf.size, Started: true}
+
+	var file models.File
+	orm.GetInstance().Connection.Where(&models.File{Path: qf.path}).FirstOrCreate(&file)
+	file.SHA256 = qf.sha256
+	file.Size = qf.size
+	file.MTime = qf.mtime
+	file.Status = models.FileUploading
+	orm.GetInstance().Connection.Save(&file)
+
+	albumID, albumName, err := u.albums.Resolve(qf)
+	if err != nil {
+		file.Status = models.FileError
+		orm.GetInstance().Connection.Save(&file)
+		u.Errors <- fmt.Errorf("can't resolve an album for %q: %w", qf.path, err)
+		return
+	}
+
+	onProgress := func(n int64) {
+		u.Progress <- ProgressEvent{WorkerID: workerID, Path: qf.path, BytesWritten: n, TotalBytes: qf.size}
+	}
+
+	if albumID != "" {
+		err = u.backend.AddToAlbum(albumID, &file, onProgress)
+	} else {
+		err = u.backend.Upload(&file, onProgress)
+	}
+	if err != nil {
+		file.Status = models.FileError
+		orm.GetInstance().Connection.Save(&file)
+		u.Errors <- fmt.Errorf("can't upload %q: %w", qf.path, err)
+		return
+	}
+
+	file.Status = models.FileSuccess
+	orm.GetInstance().Connection.Save(&file)
+	u.CompletedUploads <- UploadResult{Path: qf.path, AlbumName: albumName, Size: qf.size}
+}
+
+// WaitUploadsCompleted blocks until every file enqueued so far has finished
+// (either successfully or with an error).
+func (u *ConcurrentUploader) WaitUploadsCompleted() {
+	u.wg.Wait()
+}
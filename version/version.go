@@ -0,0 +1,10 @@
+// Package version holds build-time metadata injected via -ldflags, printed by the
+// --version flag so bug reports can include exactly which build was running.
+package version
+
+var (
+	// Hash is the git commit hash of the build, set via -ldflags "-X ...Hash=...".
+	Hash = "unknown"
+	// Date is the commit date of the build, set via -ldflags "-X ...Date=...".
+	Date = "unknown"
+)